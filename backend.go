@@ -0,0 +1,87 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+import "fmt"
+
+// Backend emits target-language code from a WSDL/XSD document gowsdl has
+// already parsed. GoWSDL.Start() delegates to whichever Backend the
+// generator was built with, so a Backend only needs to know how to turn
+// the parsed model into the handful of source sections Start() has
+// always returned ("types" and "operations"; "header"/"imports" stay
+// Go-specific and are left to the built-in backend).
+//
+// The built-in "go" backend is exactly what gowsdl generated before this
+// existed: typesTmpl run over the parsed schemas, and opsTmpl run over
+// the parsed operations. External backends targeting other languages
+// register themselves with RegisterBackend, typically from an init func,
+// and are selected with NewGoWSDL's WithBackend option or the CLI's
+// -lang flag.
+type Backend interface {
+	// Name identifies the backend for WithBackend/-lang.
+	Name() string
+	// EmitTypes emits the target language's representation of every
+	// WSDL/XSD type gowsdl parsed.
+	EmitTypes(g *GoWSDL) ([]byte, error)
+	// EmitOperations emits the per-operation request/response plumbing.
+	EmitOperations(g *GoWSDL) ([]byte, error)
+	// EmitClient emits the target language's SOAP client runtime (the
+	// transport, envelope and fault handling generated code calls into).
+	// Go needs none of its own: that runtime is the hand-maintained soap
+	// package, imported rather than generated, so the built-in backend's
+	// EmitClient is a no-op. A non-Go backend has nothing equivalent to
+	// import and uses this hook to emit its own.
+	EmitClient(g *GoWSDL) ([]byte, error)
+}
+
+// BackendFactory constructs a fresh Backend instance for one generation
+// run.
+type BackendFactory func() Backend
+
+var backends = map[string]BackendFactory{
+	"go": newGoBackend,
+}
+
+// RegisterBackend makes a Backend available under name, for use with
+// WithBackend and the CLI's -lang flag. Call it from an init func in the
+// backend's own package so importing that package for its side effect is
+// enough to make it selectable.
+func RegisterBackend(name string, factory BackendFactory) {
+	backends[name] = factory
+}
+
+func lookupBackend(name string) (Backend, error) {
+	if name == "" {
+		name = "go"
+	}
+	factory, ok := backends[name]
+	if !ok {
+		return nil, fmt.Errorf("gowsdl: unknown backend %q (import its package for registration first)", name)
+	}
+	return factory(), nil
+}
+
+// goBackend is the built-in backend and reproduces gowsdl's long-standing
+// behavior: render typesTmpl/opsTmpl against whatever the parser already
+// populated on GoWSDL.
+type goBackend struct{}
+
+func newGoBackend() Backend { return &goBackend{} }
+
+func (b *goBackend) Name() string { return "go" }
+
+func (b *goBackend) EmitTypes(g *GoWSDL) ([]byte, error) {
+	return g.genTypes()
+}
+
+func (b *goBackend) EmitOperations(g *GoWSDL) ([]byte, error) {
+	return g.genOperations()
+}
+
+// EmitClient is a no-op: generated Go code imports the soap package for
+// its client runtime instead of having one generated.
+func (b *goBackend) EmitClient(g *GoWSDL) ([]byte, error) {
+	return nil, nil
+}
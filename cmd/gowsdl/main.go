@@ -20,12 +20,26 @@ Supports only Document/Literal wrapped services, which are WS-I (http://ws-i.org
 
 Attempts to generate idiomatic Go code as much as possible.
 
-Supports WSDL 1.1, XML Schema 1.0, SOAP 1.1.
+Supports WSDL 1.1, XML Schema 1.0, SOAP 1.1 and SOAP 1.2, including MTOM/XOP
+binary attachments.
 
-Resolves external XML Schemas
+Resolves external XML Schemas, including those imported by a local WSDL
+against its own directory before falling back to HTTP.
 
 Supports providing WSDL HTTP URL as well as a local WSDL file.
 
+-cache-dir persists fetched remote schemas to disk so later generations
+can run offline, and -schema-map rewrites a schemaLocation URL to a
+local file for air-gapped CI.
+
+Code generation is backend-pluggable: -lang selects which registered
+gen.Backend emits the "types"/"operations" sections, so out-of-tree
+plugins can target other languages from the same parsed WSDL/XSD model.
+
+-enable-validation adds a Validate() method to generated types that
+carry XSD restriction facets (pattern, length, inclusive/exclusive
+bounds, totalDigits, fractionDigits).
+
 Not supported
 
 UDDI.
@@ -34,14 +48,8 @@ TODO
 
 Add support for filters to allow the user to change the generated code.
 
-If WSDL file is local, resolve external XML schemas locally too instead of failing due to not having a URL to download them from.
-
-Resolve XSD element references.
-
 Support for generating namespaces.
 
-Make code generation agnostic so generating code to other programming languages is feasible through plugins.
-
 */
 
 package main
@@ -55,8 +63,10 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 
 	gen "github.com/hooklift/gowsdl"
+	"github.com/hooklift/gowsdl/soap"
 )
 
 // Version is initialized in compilation time by go build.
@@ -71,6 +81,32 @@ var outFile = flag.String("o", "myservice.go", "File where the generated code wi
 var dir = flag.String("d", "./", "Directory under which package directory will be created")
 var insecure = flag.Bool("i", false, "Skips TLS Verification")
 var makePublic = flag.Bool("make-public", true, "Make the generated types public/exported")
+var soapVersion = flag.String("soap-version", "1.1", "SOAP version the generated client speaks: 1.1 or 1.2")
+var lang = flag.String("lang", "go", "Code-generation backend to use; \"go\" is built in, others are registered by the plugins you import")
+var enableValidation = flag.Bool("enable-validation", false, "Generate a Validate() method for types with XSD restriction facets")
+var cacheDir = flag.String("cache-dir", "", "Directory to cache fetched remote schemas in, for offline/reproducible generation")
+var schemaMap = schemaMapFlag{}
+
+func init() {
+	flag.Var(&schemaMap, "schema-map", "Rewrite a schemaLocation URL to a local file, as url=path; may be repeated")
+}
+
+// schemaMapFlag accumulates repeated -schema-map url=path flags into a
+// gen.SchemaMap.
+type schemaMapFlag gen.SchemaMap
+
+func (m schemaMapFlag) String() string {
+	return fmt.Sprint(gen.SchemaMap(m))
+}
+
+func (m schemaMapFlag) Set(value string) error {
+	url, path, ok := strings.Cut(value, "=")
+	if !ok {
+		return fmt.Errorf("invalid -schema-map %q, want url=path", value)
+	}
+	m[url] = path
+	return nil
+}
 
 func init() {
 	log.SetFlags(0)
@@ -110,7 +146,23 @@ func main() {
 		"http://www.onvif.org/ver10/schema":       "Onvif",
 		"http://docs.oasis-open.org/wsn/b-2":      "B2",
 	}
-	gowsdl, err := gen.NewGoWSDL(wsdlPath, *pkg, *insecure, *makePublic, prefix)
+	var v soap.SOAPVersion
+	switch *soapVersion {
+	case "1.2":
+		v = soap.SOAPVersion12
+	case "1.1", "":
+		v = soap.SOAPVersion11
+	default:
+		log.Fatalf("unsupported -soap-version %q, want 1.1 or 1.2", *soapVersion)
+	}
+
+	gowsdl, err := gen.NewGoWSDL(wsdlPath, *pkg, *insecure, *makePublic, prefix,
+		gen.WithSOAPVersion(v),
+		gen.WithBackend(*lang),
+		gen.WithValidation(*enableValidation),
+		gen.WithCacheDir(*cacheDir),
+		gen.WithSchemaMap(gen.SchemaMap(schemaMap)),
+	)
 	if err != nil {
 		log.Fatalln(err)
 	}
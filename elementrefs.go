@@ -0,0 +1,168 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+import "fmt"
+
+// refSymbols indexes every globally-declared xsd:element, xsd:group and
+// xsd:attributeGroup across the root schema and everything it imports,
+// keyed by "namespace localName" (see qname), the space-separated form
+// encoding/xml struct tags use for qualified names. It backs the resolveElementRef/resolveGroupRef/
+// resolveAttributeGroupRef template funcs, which is what lets a
+// ref="ns:Name" behave like an inline declaration of whatever Name
+// actually is, even when it lives in another imported namespace.
+//
+// It also keeps, per schema, the xmlns:prefix="namespace" declarations
+// that schema's own <xsd:schema> start tag carried (schema.Xmlns) --
+// this is the in-scope binding a ref's prefix has to be resolved
+// against. g.prefix is a different map entirely (namespace URI -> short
+// Go identifier, used by toGoType to name the Go type) and resolving a
+// ref through it would look up the prefix text itself as if it were a
+// namespace URI, which it never is.
+type refSymbols struct {
+	elements        map[string]*XSDElement
+	groups          map[string]*XSDGroup
+	attributeGroups map[string]*XSDAttributeGroup
+	schemaXmlns     map[string]map[string]string
+}
+
+// newRefSymbols walks schemas -- the root schema plus every xsd:import/
+// xsd:include it pulled in -- and builds the symbol table resolveXRef
+// funcs consult. It is built once per GoWSDL.Start() and reused for
+// every ref encountered while rendering typesTmpl.
+func newRefSymbols(schemas []*XSDSchema) *refSymbols {
+	s := &refSymbols{
+		elements:        make(map[string]*XSDElement),
+		groups:          make(map[string]*XSDGroup),
+		attributeGroups: make(map[string]*XSDAttributeGroup),
+		schemaXmlns:     make(map[string]map[string]string),
+	}
+	for _, schema := range schemas {
+		ns := schema.TargetNamespace
+		for i := range schema.Elements {
+			el := schema.Elements[i]
+			s.elements[qname(ns, el.Name)] = el
+		}
+		for i := range schema.Groups {
+			g := schema.Groups[i]
+			s.groups[qname(ns, g.Name)] = g
+		}
+		for i := range schema.AttributeGroups {
+			ag := schema.AttributeGroups[i]
+			s.attributeGroups[qname(ns, ag.Name)] = ag
+		}
+		s.schemaXmlns[ns] = schema.Xmlns
+	}
+	return s
+}
+
+func qname(namespace, name string) string {
+	return namespace + " " + name
+}
+
+// resolvedElement is what resolveElementRef hands to the "Elements"
+// template for a ref="..." site: everything the template would
+// otherwise have pulled off an inline xsd:element declaration. GoType
+// carries the nillability of the referenced declaration; the
+// referencing site's maxOccurs is not part of it and is applied by the
+// template itself.
+type resolvedElement struct {
+	Doc       string
+	FieldName string
+	GoType    string
+	XMLName   string
+}
+
+// resolveElementRef follows ref (an "ns:Name" or bare "Name" QName) to
+// the xsd:element it names -- in the root schema or any schema it
+// imports -- and describes it as a resolvedElement. An unresolvable ref
+// degrades to a string field named after the ref's local name rather
+// than failing the generation run, since a WSDL with a dangling ref still
+// needs to produce compilable code for its other types.
+func (g *GoWSDL) resolveElementRef(ref string) *resolvedElement {
+	local := removeNS(ref)
+	ns := g.namespaceOf(ref)
+	el, ok := g.refSymbols.elements[qname(ns, local)]
+	if !ok {
+		return &resolvedElement{
+			FieldName: makePublic(replaceReservedWords(local)),
+			GoType:    "string",
+			XMLName:   local,
+		}
+	}
+	// An element declared with an inline type has no Type attribute; the
+	// type generated for it carries the element's own name, so the ref
+	// itself is what toGoType is given in that case.
+	xsdType := el.Type
+	if xsdType == "" {
+		xsdType = ref
+	}
+	return &resolvedElement{
+		Doc:       el.Doc,
+		FieldName: makePublic(replaceReservedWords(el.Name)),
+		GoType:    toGoType(xsdType, el.Nillable),
+		XMLName:   el.Name,
+	}
+}
+
+// resolveGroupRef follows a xsd:group ref to the XSDGroup it names and
+// returns the elements its model group declares, so {{template
+// "Elements" (resolveGroupRef .Ref)}} can splice them into the
+// referencing complex type exactly as if they'd been written inline.
+func (g *GoWSDL) resolveGroupRef(ref string) []*XSDElement {
+	local := removeNS(ref)
+	ns := g.namespaceOf(ref)
+	group, ok := g.refSymbols.groups[qname(ns, local)]
+	if !ok {
+		return nil
+	}
+	var elements []*XSDElement
+	elements = append(elements, group.Sequence...)
+	elements = append(elements, group.Choice...)
+	elements = append(elements, group.All...)
+	return elements
+}
+
+// resolveAttributeGroupRef follows a xsd:attributeGroup ref to the
+// XSDAttributeGroup it names and returns its attributes, so {{template
+// "Attributes" (resolveAttributeGroupRef .Ref)}} can splice them into
+// the referencing complex type exactly as if they'd been written
+// inline.
+func (g *GoWSDL) resolveAttributeGroupRef(ref string) []*XSDAttribute {
+	local := removeNS(ref)
+	ns := g.namespaceOf(ref)
+	group, ok := g.refSymbols.attributeGroups[qname(ns, local)]
+	if !ok {
+		return nil
+	}
+	return group.Attributes
+}
+
+// namespaceOf resolves the prefix on a QName like "tns:Foo" against the
+// xmlns declarations of the schema currently being rendered (getNS),
+// since that is the scope ref's prefix was actually written in, falling
+// back to that same schema's own namespace for an unprefixed name.
+func (g *GoWSDL) namespaceOf(qName string) string {
+	prefix, local := splitQName(qName)
+	current := g.getNS()
+	if prefix == "" {
+		return current
+	}
+	if xmlns, ok := g.refSymbols.schemaXmlns[current]; ok {
+		if ns, ok := xmlns[prefix]; ok {
+			return ns
+		}
+	}
+	return fmt.Sprintf("%s:%s", prefix, local)
+}
+
+func splitQName(qName string) (prefix, local string) {
+	for i := 0; i < len(qName); i++ {
+		if qName[i] == ':' {
+			return qName[:i], qName[i+1:]
+		}
+	}
+	return "", qName
+}
@@ -15,18 +15,34 @@ var opsTmpl = `
 			{{$soapAction := findSOAPAction .Name $privateType}}
 			{{$requestType := findType .Input.Message | replaceReservedWords | makePublic}}
 			{{$responseType := findType .Output.Message | replaceReservedWords | makePublic}}
+			{{$opName := makePublic .Name | replaceReservedWords}}
 
 			{{/*if ne $soapAction ""*/}}
 			{{if gt $faults 0}}
 			// Error can be either of the following types:
 			// {{range .Faults}}
-			//   - {{.Name}} {{.Doc}}{{end}}{{end}}
+			//   - *{{$opName}}{{.Name | makePublic}}Fault {{.Doc}}{{end}}{{end}}
 			{{if ne .Doc ""}}/* {{.Doc}} */{{end}}
 			{{makePublic .Name | replaceReservedWords}} (ctx context.Context, {{if ne $requestType ""}}request *{{$requestType}}{{end}}) ({{if ne $responseType ""}}*{{$responseType}}, {{end}}error)
 			{{/*end*/}}
 		{{end}}
 	}
 
+	{{range .Operations}}
+		{{if gt (len .Faults) 0}}
+			{{$opName := makePublic .Name | replaceReservedWords}}
+			{{range .Faults}}
+				{{$faultType := print $opName (.Name | makePublic) "Fault"}}
+				// {{$faultType}} is returned by {{$opName}} when the server
+				// signals a {{.Name}} SOAP fault.{{if ne .Doc ""}} {{.Doc}}{{end}}
+				type {{$faultType}} struct {
+					*soap.Fault
+					Detail {{findType .Message | replaceReservedWords | makePublic}}
+				}
+			{{end}}
+		{{end}}
+	{{end}}
+
 	type {{$privateType}} struct {
 		client *soap.Client
 	}
@@ -41,6 +57,7 @@ var opsTmpl = `
 		{{$requestType := findType .Input.Message | replaceReservedWords | makePublic}}
 		{{$soapAction := findSOAPAction .Name $privateType}}
 		{{$responseType := findType .Output.Message | replaceReservedWords | makePublic}}
+		{{$opName := makePublic .Name | replaceReservedWords}}
 
 		type {{$requestType}}Body struct {
 			{{$requestType}} {{$requestType}}
@@ -53,7 +70,7 @@ var opsTmpl = `
 
 		func (service *{{$privateType}}) {{makePublic .Name | replaceReservedWords}} (ctx context.Context, {{if ne $requestType ""}}request *{{$requestType}}{{end}}) ({{if ne $responseType ""}}*{{$responseType}}, {{end}}error) {
 
-			envelope := soap.NewEnvelope()
+			envelope := service.client.NewEnvelope()
 			envelope.Body = &request
 
 			response := new({{$responseType}}Body)
@@ -64,6 +81,25 @@ var opsTmpl = `
 				"{{if ne $soapAction ""}}{{$soapAction}}{{else}}''{{end}}",
 				envelope, envelopeResp)
 			if err != nil {
+				{{if gt (len .Faults) 0}}
+				var soapFault *soap.Fault
+				if errors.As(err, &soapFault) {
+					// Dispatch on the detail payload's own root element name
+					// (findNameByType gives the element a type is wrapped in
+					// on the wire), not the wsdl:fault's symbolic name --
+					// message part and fault name are two independent
+					// identifiers and need not match.
+					switch soapFault.DetailName() {
+					{{range .Faults}}
+					case "{{findNameByType (findType .Message)}}":
+						detail := new({{findType .Message | replaceReservedWords | makePublic}})
+						if detailErr := soapFault.Detail().Unmarshal(detail); detailErr == nil {
+							return {{if ne $responseType ""}}nil, {{end}}&{{print $opName (.Name | makePublic) "Fault"}}{Fault: soapFault, Detail: *detail}
+						}
+					{{end}}
+					}
+				}
+				{{end}}
 				return {{if ne $responseType ""}}nil, {{end}}err
 			}
 			return {{if ne $responseType ""}}&response.{{$responseType}}, {{end}}nil
@@ -0,0 +1,65 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+import "github.com/hooklift/gowsdl/soap"
+
+// Option configures the generator returned by NewGoWSDL, which now
+// accepts a variadic list of these alongside its existing positional
+// arguments.
+type Option func(*GoWSDL)
+
+// WithSOAPVersion records whether generated code targets SOAP 1.1 (the
+// default, unchanged behavior) or SOAP 1.2. opsTmpl does not read it:
+// the generated operations build their envelope with the soap.Client
+// they are given, so the envelope namespace and content-type on the
+// wire follow that client's own soap.WithSOAPVersion setting.
+func WithSOAPVersion(v soap.SOAPVersion) Option {
+	return func(g *GoWSDL) {
+		g.soapVersion = v
+	}
+}
+
+// WithValidation makes typesTmpl emit a Validate() method for every
+// generated type that carries XSD restriction facets (pattern, length,
+// inclusive/exclusive bounds, totalDigits, fractionDigits), so callers
+// can check a value against its schema without a round trip.
+func WithValidation(enabled bool) Option {
+	return func(g *GoWSDL) {
+		g.enableValidation = enabled
+	}
+}
+
+// WithCacheDir persists schemas fetched over HTTP to dir, keyed by a
+// hash of their URL, so later generations against the same WSDL can run
+// offline and reproduce identical output.
+func WithCacheDir(dir string) Option {
+	return func(g *GoWSDL) {
+		g.cacheDir = dir
+	}
+}
+
+// WithSchemaMap rewrites schemaLocation URLs to local files before
+// they're resolved, per m. Useful in air-gapped CI where a WSDL's
+// imports can't be fetched over the network at all.
+func WithSchemaMap(m SchemaMap) Option {
+	return func(g *GoWSDL) {
+		g.schemaMap = m
+	}
+}
+
+// WithBackend selects which registered Backend generates code for the
+// "types" and "operations" sections of Start()'s output. Defaults to the
+// built-in "go" backend; see RegisterBackend to add others.
+func WithBackend(name string) Option {
+	return func(g *GoWSDL) {
+		backend, err := lookupBackend(name)
+		if err != nil {
+			g.err = err
+			return
+		}
+		g.backend = backend
+	}
+}
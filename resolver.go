@@ -0,0 +1,161 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+// SchemaMap rewrites a remote schemaLocation URL to a local file path
+// before it is resolved, so WSDLs that import schemas by URL can still
+// be generated from in air-gapped CI.
+type SchemaMap map[string]string
+
+// resolver resolves the schemaLocation/location attributes of
+// xsd:import, xsd:include and wsdl:import. When the root WSDL is a
+// local file, sibling schemaLocations are first looked up relative to
+// its directory; when it's remote, a relative schemaLocation is
+// instead joined against the WSDL's own URL. Only locations that don't
+// resolve either of those ways, or that were already absolute URLs, go
+// over HTTP as-is. A cacheDir, when set, makes fetched remote schemas
+// reproducible offline on later runs.
+type resolver struct {
+	wsdlDir   string
+	wsdlURL   *url.URL
+	cacheDir  string
+	schemaMap SchemaMap
+	insecure  bool
+}
+
+func newResolver(wsdlPath, cacheDir string, schemaMap SchemaMap, insecure bool) *resolver {
+	dir := ""
+	var base *url.URL
+	if isURL(wsdlPath) {
+		base, _ = url.Parse(wsdlPath)
+	} else {
+		dir = filepath.Dir(wsdlPath)
+	}
+	return &resolver{
+		wsdlDir:   dir,
+		wsdlURL:   base,
+		cacheDir:  cacheDir,
+		schemaMap: schemaMap,
+		insecure:  insecure,
+	}
+}
+
+// resolve returns the contents of the schema at location, which may be
+// a bare filename, a path relative to the root WSDL, or a full URL.
+func (r *resolver) resolve(location string) ([]byte, error) {
+	mapped, wasMapped := r.schemaMap[location]
+	if wasMapped {
+		location = mapped
+	}
+
+	if !isURL(location) {
+		if wasMapped {
+			// SchemaMap exists to substitute a local file for
+			// whatever the schemaLocation would otherwise resolve
+			// to, remote root WSDL or not, so a mapped non-URL
+			// path is always read from disk, never joined against
+			// the WSDL's URL.
+			data, err := os.ReadFile(location)
+			if err != nil {
+				return nil, fmt.Errorf("resolving mapped schema %q: %w", location, err)
+			}
+			return data, nil
+		}
+		if r.wsdlURL != nil {
+			// The root WSDL is itself remote, so a relative
+			// schemaLocation has to be resolved against its URL
+			// rather than looked up on disk.
+			if ref, err := url.Parse(location); err == nil {
+				location = r.wsdlURL.ResolveReference(ref).String()
+			}
+		} else {
+			path := location
+			if r.wsdlDir != "" && !filepath.IsAbs(path) {
+				path = filepath.Join(r.wsdlDir, path)
+			}
+			if data, err := os.ReadFile(path); err == nil {
+				return data, nil
+			} else if r.wsdlDir == "" {
+				return nil, fmt.Errorf("resolving local schema %q: %w", location, err)
+			}
+			// Fall through: the location was not found on disk. It
+			// is not an absolute URL either (isURL is false here),
+			// so the HTTP fetch below returns an error for it.
+		}
+	}
+
+	if r.cacheDir != "" {
+		if data, err := r.readCache(location); err == nil {
+			return data, nil
+		}
+	}
+
+	data, err := r.fetch(location)
+	if err != nil {
+		return nil, fmt.Errorf("resolving schema %q: %w", location, err)
+	}
+
+	if r.cacheDir != "" {
+		if err := r.writeCache(location, data); err != nil {
+			return nil, fmt.Errorf("caching schema %q: %w", location, err)
+		}
+	}
+	return data, nil
+}
+
+func (r *resolver) fetch(location string) ([]byte, error) {
+	client := http.DefaultClient
+	if r.insecure {
+		client = insecureHTTPClient()
+	}
+	resp, err := client.Get(location)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (r *resolver) cachePath(location string) string {
+	sum := sha256.Sum256([]byte(location))
+	return filepath.Join(r.cacheDir, hex.EncodeToString(sum[:])+".xsd")
+}
+
+func (r *resolver) readCache(location string) ([]byte, error) {
+	return os.ReadFile(r.cachePath(location))
+}
+
+func (r *resolver) writeCache(location string, data []byte) error {
+	if err := os.MkdirAll(r.cacheDir, 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(r.cachePath(location), data, 0o644)
+}
+
+func insecureHTTPClient() *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}, //nolint:gosec // opt-in via -i
+	}
+}
+
+func isURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && u.Scheme != "" && u.Host != ""
+}
@@ -0,0 +1,48 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package soap
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// TotalDigits counts the decimal digits in v's decimal representation,
+// on both sides of the decimal point and ignoring any sign, backing the
+// generated Validate check for the XSD totalDigits facet.
+func TotalDigits(v interface{}) int {
+	digits := 0
+	for _, r := range fmt.Sprintf("%v", v) {
+		if r >= '0' && r <= '9' {
+			digits++
+		}
+	}
+	return digits
+}
+
+// FractionDigits counts the decimal digits after the decimal point in
+// v's decimal representation, backing the generated Validate check for
+// the XSD fractionDigits facet.
+func FractionDigits(v interface{}) int {
+	s := fmt.Sprintf("%v", v)
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		return len(s) - i - 1
+	}
+	return 0
+}
+
+// NumericValue parses v's decimal text representation as a float64,
+// backing the generated Validate check for a minInclusive/maxInclusive/
+// minExclusive/maxExclusive facet. XSD permits those facets on a
+// restriction base that a generated type may represent as a Go bool or
+// string rather than a numeric type (e.g. xsd:dateTime), which a bare
+// float64(x) conversion in the generated code can't handle; ok is false
+// for any v that doesn't parse as a number, and the generated check
+// skips the bound in that case rather than failing to compile.
+func NumericValue(v interface{}) (f float64, ok bool) {
+	f, err := strconv.ParseFloat(fmt.Sprintf("%v", v), 64)
+	return f, err == nil
+}
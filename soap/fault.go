@@ -0,0 +1,128 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package soap
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+)
+
+// FaultDetail holds the raw <detail>/<Detail> payload of a SOAP fault as
+// unparsed XML, deferred this way so fault-dispatching code generated
+// for a specific operation can re-unmarshal it into whichever typed
+// fault its wsdl:fault actually declared.
+type FaultDetail struct {
+	XML []byte `xml:",innerxml"`
+}
+
+// Name reports the local name of the detail payload's root element, so
+// generated dispatch code can switch on it without guessing which
+// wsdl:fault produced this response. ok is false when there was no
+// detail payload at all.
+func (d FaultDetail) Name() (name string, ok bool) {
+	if len(d.XML) == 0 {
+		return "", false
+	}
+	dec := xml.NewDecoder(bytes.NewReader(d.XML))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return "", false
+		}
+		if se, isStart := tok.(xml.StartElement); isStart {
+			return se.Name.Local, true
+		}
+	}
+}
+
+// Unmarshal decodes the detail payload into v. d.XML is the detail
+// element's innerxml, i.e. already the single element gowsdl generated
+// a type for (the one Name reports), so it is unmarshaled as-is rather
+// than re-wrapped in another root.
+func (d FaultDetail) Unmarshal(v interface{}) error {
+	if len(d.XML) == 0 {
+		return nil
+	}
+	return xml.Unmarshal(d.XML, v)
+}
+
+// Fault12Code is the SOAP 1.2 faultcode shape: a top-level Value plus an
+// optional, more specific Subcode.
+type Fault12Code struct {
+	Value   string `xml:"Value"`
+	Subcode struct {
+		Value string `xml:"Value"`
+	} `xml:"Subcode"`
+}
+
+// Fault12Reason is the SOAP 1.2 faultstring shape: one or more
+// language-tagged Text entries; only one is kept since gowsdl's
+// generated clients don't negotiate a response language. When there
+// are several, Text holds the last one.
+type Fault12Reason struct {
+	Text string `xml:"Text"`
+}
+
+// Fault is the generic SOAP fault shape, covering both SOAP 1.1
+// (faultcode/faultstring/faultactor/detail) and SOAP 1.2
+// (Code/Reason/Detail) wire formats in a single struct -- their element
+// names never collide, so both decode into the same value and only the
+// fields for whichever version the server actually spoke end up
+// populated. Operations that declare wsdl:fault messages get a more
+// specific, typed error instead; this is what callers see when the
+// server returns a fault gowsdl doesn't have a generated type for.
+type Fault struct {
+	Code11   string      `xml:"faultcode"`
+	String11 string      `xml:"faultstring"`
+	Actor    string      `xml:"faultactor"`
+	Detail11 FaultDetail `xml:"detail"`
+
+	Code12   Fault12Code   `xml:"Code"`
+	Reason12 Fault12Reason `xml:"Reason"`
+	Detail12 FaultDetail   `xml:"Detail"`
+}
+
+// Code returns the fault code regardless of which SOAP version produced
+// it, preferring the SOAP 1.2 Subcode when present since it is the more
+// specific of the two.
+func (f *Fault) Code() string {
+	if f.Code12.Subcode.Value != "" {
+		return f.Code12.Subcode.Value
+	}
+	if f.Code12.Value != "" {
+		return f.Code12.Value
+	}
+	return f.Code11
+}
+
+// Message returns the human-readable fault text for either SOAP
+// version.
+func (f *Fault) Message() string {
+	if f.Reason12.Text != "" {
+		return f.Reason12.Text
+	}
+	return f.String11
+}
+
+// Detail returns the fault's detail/Detail payload for either SOAP
+// version.
+func (f *Fault) Detail() FaultDetail {
+	if len(f.Detail12.XML) > 0 {
+		return f.Detail12
+	}
+	return f.Detail11
+}
+
+// DetailName reports the local name of the fault's detail payload, or
+// "" if it had none.
+func (f *Fault) DetailName() string {
+	name, _ := f.Detail().Name()
+	return name
+}
+
+func (f *Fault) Error() string {
+	return fmt.Sprintf("soap fault: %s: %s", f.Code(), f.Message())
+}
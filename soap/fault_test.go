@@ -0,0 +1,88 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package soap
+
+import (
+	"encoding/xml"
+	"testing"
+)
+
+type invalidAccountFault struct {
+	XMLName xml.Name `xml:"http://example.com/faults InvalidAccount"`
+	Reason  string   `xml:"Reason"`
+}
+
+func TestFaultDetailNameSOAP11(t *testing.T) {
+	const body = `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+		<soap:Body>
+			<soap:Fault>
+				<faultcode>soap:Server</faultcode>
+				<faultstring>invalid account</faultstring>
+				<detail><InvalidAccount xmlns="http://example.com/faults"><Reason>closed</Reason></InvalidAccount></detail>
+			</soap:Fault>
+		</soap:Body>
+	</soap:Envelope>`
+
+	var env struct {
+		Body struct {
+			Fault Fault `xml:"Fault"`
+		} `xml:"Body"`
+	}
+	if err := xml.Unmarshal([]byte(body), &env); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	f := &env.Body.Fault
+
+	if name := f.DetailName(); name != "InvalidAccount" {
+		t.Fatalf("DetailName() = %q, want InvalidAccount", name)
+	}
+
+	var detail invalidAccountFault
+	if err := f.Detail().Unmarshal(&detail); err != nil {
+		t.Fatalf("Detail().Unmarshal: %v", err)
+	}
+	if detail.Reason != "closed" {
+		t.Fatalf("detail.Reason = %q, want closed", detail.Reason)
+	}
+	if f.Message() != "invalid account" {
+		t.Fatalf("Message() = %q, want %q", f.Message(), "invalid account")
+	}
+}
+
+func TestFaultDetailNameSOAP12(t *testing.T) {
+	const body = `<soap:Envelope xmlns:soap="http://www.w3.org/2003/05/soap-envelope">
+		<soap:Body>
+			<soap:Fault>
+				<soap:Code><soap:Value>soap:Sender</soap:Value><soap:Subcode><soap:Value>rpc:BadArguments</soap:Value></soap:Subcode></soap:Code>
+				<soap:Reason><soap:Text>bad arguments</soap:Text></soap:Reason>
+				<soap:Detail><InvalidAccount xmlns="http://example.com/faults"><Reason>missing</Reason></InvalidAccount></soap:Detail>
+			</soap:Fault>
+		</soap:Body>
+	</soap:Envelope>`
+
+	var env struct {
+		Body struct {
+			Fault Fault `xml:"Fault"`
+		} `xml:"Body"`
+	}
+	if err := xml.Unmarshal([]byte(body), &env); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	f := &env.Body.Fault
+
+	if code := f.Code(); code != "rpc:BadArguments" {
+		t.Fatalf("Code() = %q, want rpc:BadArguments", code)
+	}
+	if name := f.DetailName(); name != "InvalidAccount" {
+		t.Fatalf("DetailName() = %q, want InvalidAccount", name)
+	}
+}
+
+func TestFaultDetailNameNoDetail(t *testing.T) {
+	f := &Fault{Code11: "soap:Server", String11: "boom"}
+	if name := f.DetailName(); name != "" {
+		t.Fatalf("DetailName() = %q, want empty", name)
+	}
+}
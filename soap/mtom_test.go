@@ -0,0 +1,134 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package soap
+
+import (
+	"bytes"
+	"encoding/xml"
+	"mime/multipart"
+	"net/textproto"
+	"testing"
+)
+
+type mtomTestRequest struct {
+	XMLName xml.Name        `xml:"http://example.com/mtom UploadRequest"`
+	Name    string          `xml:"Name"`
+	File    *MTOMAttachment `xml:"File"`
+}
+
+func TestEncodeDecodeMTOMRoundTrip(t *testing.T) {
+	attachment := &MTOMAttachment{Bytes: []byte("binary payload"), ContentType: "application/octet-stream"}
+	req := &mtomTestRequest{Name: "report.bin", File: attachment}
+
+	attachments := collectAttachments(req)
+	if len(attachments) != 1 {
+		t.Fatalf("collectAttachments found %d attachments, want 1", len(attachments))
+	}
+
+	body, contentType, err := encodeMTOM(req, attachments, "text/xml")
+	if err != nil {
+		t.Fatalf("encodeMTOM: %v", err)
+	}
+
+	mediaType, params, ok := multipartContentType(contentType)
+	if !ok {
+		t.Fatalf("multipartContentType(%q) returned ok=false", contentType)
+	}
+	if mediaType != "multipart/related" {
+		t.Fatalf("mediaType = %q, want multipart/related", mediaType)
+	}
+	if params["start-info"] != "text/xml" {
+		t.Fatalf("start-info = %q, want text/xml", params["start-info"])
+	}
+
+	var decoded mtomTestRequest
+	if err := decodeMTOM(mediaType, params, body, &decoded); err != nil {
+		t.Fatalf("decodeMTOM: %v", err)
+	}
+	if decoded.Name != req.Name {
+		t.Fatalf("decoded.Name = %q, want %q", decoded.Name, req.Name)
+	}
+	if decoded.File == nil || !bytes.Equal(decoded.File.Bytes, attachment.Bytes) {
+		t.Fatalf("decoded.File.Bytes = %v, want %v", decoded.File, attachment.Bytes)
+	}
+}
+
+// TestMTOMAttachmentMarshalXML guards the wire shape a real MTOM/XOP
+// unbundler expects: the xop:Include placeholder nested inside the
+// field's own element, not folded into that element's attributes.
+func TestMTOMAttachmentMarshalXML(t *testing.T) {
+	req := &mtomTestRequest{Name: "x", File: &MTOMAttachment{Bytes: []byte("b")}}
+	collectAttachments(req)
+
+	b, err := xml.Marshal(req)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	want := `<File><Include xmlns="http://www.w3.org/2004/08/xop/include" href="cid:attachment-1@gowsdl"></Include></File>`
+	if !bytes.Contains(b, []byte(want)) {
+		t.Fatalf("marshaled XML = %s, want it to contain %s", b, want)
+	}
+}
+
+// TestDecodeMTOMRootPartOutOfOrder guards decodeMTOM against a
+// third-party MTOM sender that doesn't write the root part first:
+// the root has to be identified by matching the "start" param against
+// each part's Content-ID, not assumed to be whichever part comes first.
+func TestDecodeMTOMRootPartOutOfOrder(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := multipart.NewWriter(buf)
+	w.SetBoundary("boundary42")
+
+	attachmentHeader := textproto.MIMEHeader{}
+	attachmentHeader.Set("Content-ID", "<attachment-1@gowsdl>")
+	attachmentPart, err := w.CreatePart(attachmentHeader)
+	if err != nil {
+		t.Fatalf("CreatePart(attachment): %v", err)
+	}
+	if _, err := attachmentPart.Write([]byte("binary payload")); err != nil {
+		t.Fatalf("write attachment: %v", err)
+	}
+
+	rootHeader := textproto.MIMEHeader{}
+	rootHeader.Set("Content-ID", "<root.message@gowsdl>")
+	rootPart, err := w.CreatePart(rootHeader)
+	if err != nil {
+		t.Fatalf("CreatePart(root): %v", err)
+	}
+	rootXML, err := xml.Marshal(&mtomTestRequest{Name: "out-of-order", File: &MTOMAttachment{contentID: "attachment-1@gowsdl"}})
+	if err != nil {
+		t.Fatalf("marshal root: %v", err)
+	}
+	if _, err := rootPart.Write(rootXML); err != nil {
+		t.Fatalf("write root: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	params := map[string]string{
+		"boundary": "boundary42",
+		"start":    "<root.message@gowsdl>",
+	}
+
+	var decoded mtomTestRequest
+	if err := decodeMTOM("multipart/related", params, buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("decodeMTOM: %v", err)
+	}
+	if decoded.Name != "out-of-order" {
+		t.Fatalf("decoded.Name = %q, want out-of-order (decoded the attachment part as the root)", decoded.Name)
+	}
+	if decoded.File == nil || !bytes.Equal(decoded.File.Bytes, []byte("binary payload")) {
+		t.Fatalf("decoded.File.Bytes = %v, want the attachment part's bytes", decoded.File)
+	}
+}
+
+func TestDecodeMTOMMissingBoundary(t *testing.T) {
+	var decoded mtomTestRequest
+	err := decodeMTOM("multipart/related", map[string]string{}, nil, &decoded)
+	if err == nil {
+		t.Fatal("decodeMTOM with no boundary param returned nil error")
+	}
+}
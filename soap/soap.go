@@ -0,0 +1,283 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+// Package soap provides the runtime support used by the code gowsdl
+// generates: the SOAP envelope types and the HTTP client that knows how
+// to send and receive them.
+package soap
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// SOAPVersion selects the envelope namespace, content-type and fault
+// shape used when talking to a service.
+type SOAPVersion string
+
+const (
+	// SOAPVersion11 is the default, and what gowsdl has always generated.
+	SOAPVersion11 SOAPVersion = "1.1"
+	// SOAPVersion12 switches the client to the SOAP 1.2 envelope
+	// namespace and "application/soap+xml" content-type.
+	SOAPVersion12 SOAPVersion = "1.2"
+)
+
+const (
+	soap11Namespace   = "http://schemas.xmlsoap.org/soap/envelope/"
+	soap12Namespace   = "http://www.w3.org/2003/05/soap-envelope"
+	soap11ContentType = `text/xml; charset="utf-8"`
+	soap12ContentType = `application/soap+xml; charset="utf-8"`
+)
+
+// Client is a SOAP HTTP client.
+type Client struct {
+	url          string
+	tls          *tls.Config
+	httpClient   *http.Client
+	soapVersion  SOAPVersion
+	headers      []interface{}
+	wsAddressing *wsAddressingConfig
+	wsSecurity   WSSecurity
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient overrides the http.Client used to make requests.
+func WithHTTPClient(c *http.Client) Option {
+	return func(s *Client) {
+		s.httpClient = c
+	}
+}
+
+// WithTLS sets the TLS config used when the default http.Client is used.
+func WithTLS(tls *tls.Config) Option {
+	return func(s *Client) {
+		s.tls = tls
+	}
+}
+
+// WithSOAPVersion selects SOAP 1.1 (the default) or SOAP 1.2 framing for
+// every call made through this client.
+func WithSOAPVersion(v SOAPVersion) Option {
+	return func(s *Client) {
+		s.soapVersion = v
+	}
+}
+
+// WithHeader adds a SOAP header that is sent with every request made
+// through this client, in addition to any headers set on a single call.
+func WithHeader(header interface{}) Option {
+	return func(s *Client) {
+		s.headers = append(s.headers, header)
+	}
+}
+
+// NewClient creates a SOAP client pointed at url.
+func NewClient(url string, opts ...Option) *Client {
+	client := &Client{
+		url:         url,
+		soapVersion: SOAPVersion11,
+	}
+	for _, opt := range opts {
+		opt(client)
+	}
+	if client.httpClient == nil {
+		client.httpClient = &http.Client{}
+		if client.tls != nil {
+			client.httpClient.Transport = &http.Transport{TLSClientConfig: client.tls}
+		}
+	}
+	return client
+}
+
+// SOAPVersion reports the envelope version this client was configured
+// with.
+func (c *Client) SOAPVersion() SOAPVersion {
+	if c.soapVersion == "" {
+		return SOAPVersion11
+	}
+	return c.soapVersion
+}
+
+// Header is a generic SOAP header entry.
+type Header struct {
+	Items []interface{} `xml:",omitempty"`
+}
+
+// Envelope is a SOAP envelope, in either the 1.1 or 1.2 namespace
+// depending on which Client built it.
+type Envelope struct {
+	XMLName xml.Name
+	Header  *Header     `xml:"Header,omitempty"`
+	Body    interface{} `xml:"Body"`
+}
+
+// EnvelopeResponseBody lets generated response bodies embed a common
+// field set; Fault is populated instead of the operation's response
+// type whenever the server returns a soap:Fault.
+type EnvelopeResponseBody struct {
+	Fault *Fault `xml:"Fault,omitempty"`
+}
+
+// SOAPFault returns the fault carried by this response body, or nil if
+// the server didn't return one. Embedding EnvelopeResponseBody gives
+// every generated response body this method for free, which is how
+// Client.Call recognizes a soap:Fault without needing to know the
+// concrete, per-operation response type it was asked to decode into.
+func (b *EnvelopeResponseBody) SOAPFault() *Fault {
+	return b.Fault
+}
+
+// EnvelopeResponse is the envelope a response is unmarshaled into.
+type EnvelopeResponse struct {
+	XMLName xml.Name
+	Header  *Header     `xml:"Header,omitempty"`
+	Body    interface{} `xml:"Body"`
+}
+
+// NewEnvelope builds an empty SOAP 1.1 envelope. Kept for backwards
+// compatibility with generated code that doesn't have access to a
+// Client; prefer Client.NewEnvelope so the envelope matches the
+// client's configured SOAPVersion.
+func NewEnvelope() *Envelope {
+	return &Envelope{XMLName: xml.Name{Space: soap11Namespace, Local: "Envelope"}}
+}
+
+// NewEnvelope builds an empty envelope in this client's configured
+// SOAP version.
+func (c *Client) NewEnvelope() *Envelope {
+	ns := soap11Namespace
+	if c.SOAPVersion() == SOAPVersion12 {
+		ns = soap12Namespace
+	}
+	env := &Envelope{XMLName: xml.Name{Space: ns, Local: "Envelope"}}
+	if len(c.headers) > 0 {
+		env.Header = &Header{Items: c.headers}
+	}
+	return env
+}
+
+func (c *Client) contentType(soapAction string) string {
+	if c.SOAPVersion() == SOAPVersion12 {
+		ct := soap12ContentType
+		if soapAction != "" {
+			ct += fmt.Sprintf(`; action="%s"`, soapAction)
+		}
+		return ct
+	}
+	return soap11ContentType
+}
+
+// xopContentType is the bare SOAP media type ("text/xml" or
+// "application/soap+xml") this client negotiated, with none of
+// contentType's charset/action params -- it's what an MTOM root part's
+// "type" param and the outer multipart's "start-info" param need to
+// name, so a strict XOP unbundler can confirm what it'll get once it
+// unwraps the root part.
+func (c *Client) xopContentType() string {
+	if c.SOAPVersion() == SOAPVersion12 {
+		return "application/soap+xml"
+	}
+	return "text/xml"
+}
+
+// Call sends request wrapped in a SOAP envelope to soapAction and
+// decodes the response into response. When request or response contain
+// MTOMAttachment fields the call is transparently encoded/decoded as
+// multipart/related MTOM instead of a plain XML body.
+func (c *Client) Call(ctx context.Context, soapAction string, request, response interface{}) error {
+	// Assigned before WSSecurity.Apply runs: X509Signer replaces env.Body
+	// with an opaque, already-rendered byte copy once it signs it, so any
+	// MTOMAttachment field has to have its real content-id (used by both
+	// the xop:Include href marshaled into that copy and the MIME part
+	// below) in place beforehand, not discovered by walking the envelope
+	// afterward.
+	attachments := collectAttachments(request)
+
+	if env, ok := request.(*Envelope); ok {
+		if headers := c.wsAddressingHeaders(soapAction); len(headers) > 0 {
+			if env.Header == nil {
+				env.Header = &Header{}
+			}
+			env.Header.Items = append(env.Header.Items, headers...)
+		}
+		if c.wsSecurity != nil {
+			if err := c.wsSecurity.Apply(env); err != nil {
+				return fmt.Errorf("soap: applying WS-Security: %w", err)
+			}
+		}
+	}
+
+	var body []byte
+	var contentType string
+	var err error
+	if len(attachments) > 0 {
+		body, contentType, err = encodeMTOM(request, attachments, c.xopContentType())
+	} else {
+		body, err = xml.Marshal(request)
+		contentType = c.contentType(soapAction)
+	}
+	if err != nil {
+		return fmt.Errorf("soap: marshaling request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("soap: building request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	if c.SOAPVersion() == SOAPVersion11 && soapAction != "" {
+		req.Header.Set("SOAPAction", soapAction)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("soap: performing request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("soap: reading response: %w", err)
+	}
+
+	if mediaType, params, ok := multipartContentType(resp.Header.Get("Content-Type")); ok {
+		if err := decodeMTOM(mediaType, params, respBody, response); err != nil {
+			return err
+		}
+		return faultFrom(response)
+	}
+
+	if err := xml.Unmarshal(respBody, response); err != nil {
+		return err
+	}
+	return faultFrom(response)
+}
+
+// faultFrom reports the soap:Fault carried by response, if any, as an
+// error. response is always the *EnvelopeResponse Call was asked to
+// decode into; its Body is whatever response body struct the generated
+// operation method supplied, which gets this check for free by
+// embedding EnvelopeResponseBody.
+func faultFrom(response interface{}) error {
+	env, ok := response.(*EnvelopeResponse)
+	if !ok {
+		return nil
+	}
+	faulter, ok := env.Body.(interface{ SOAPFault() *Fault })
+	if !ok {
+		return nil
+	}
+	if f := faulter.SOAPFault(); f != nil {
+		return f
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package soap
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type callTestBody struct {
+	XMLName xml.Name        `xml:"http://example.com/call UploadRequest"`
+	File    *MTOMAttachment `xml:"File"`
+}
+
+// TestCallSignsAndKeepsAttachment guards against a WSSecurity
+// implementation that touches env.Body (only X509Signer does) making
+// Call think a request has no MTOM attachments: if attachment content-
+// IDs aren't assigned until after WSSecurity.Apply, the signed Body is
+// marshaled with an empty "cid:" href and Call takes the plain-XML
+// branch instead of encodeMTOM, silently dropping the binary payload.
+func TestCallSignsAndKeepsAttachment(t *testing.T) {
+	var gotContentType string
+	var gotBody []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotContentType = r.Header.Get("Content-Type")
+		gotBody, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/xml")
+		_, _ = w.Write([]byte(`<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Body></Body></Envelope>`))
+	}))
+	defer server.Close()
+
+	cert, key := selfSignedCert(t)
+	client := NewClient(server.URL, WithWSSecurity(X509Signer{Cert: cert, Key: key}))
+
+	request := &callTestBody{File: &MTOMAttachment{Bytes: []byte("binary payload"), ContentType: "application/octet-stream"}}
+	envelope := client.NewEnvelope()
+	envelope.Body = request
+
+	var response EnvelopeResponse
+	if err := client.Call(context.Background(), "", envelope, &response); err != nil {
+		t.Fatalf("Call: %v", err)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(gotContentType)
+	if err != nil || mediaType != "multipart/related" {
+		t.Fatalf("Content-Type = %q, want a multipart/related MTOM request (err=%v)", gotContentType, err)
+	}
+
+	reader := multipart.NewReader(strings.NewReader(string(gotBody)), params["boundary"])
+	var rootXML []byte
+	attachmentSeen := false
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("reading part: %v", err)
+		}
+		data, err := io.ReadAll(part)
+		if err != nil {
+			t.Fatalf("reading part body: %v", err)
+		}
+		if rootXML == nil {
+			rootXML = data
+			continue
+		}
+		if string(data) == "binary payload" {
+			attachmentSeen = true
+		}
+	}
+
+	if !attachmentSeen {
+		t.Fatal("request body has no MIME part carrying the attachment bytes")
+	}
+	if strings.Contains(string(rootXML), `href="cid:"`) {
+		t.Fatalf("root part references an empty cid, attachment content-id wasn't assigned before signing: %s", rootXML)
+	}
+}
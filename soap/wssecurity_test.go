@@ -0,0 +1,126 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package soap
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/xml"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+type wssecTestBody struct {
+	XMLName xml.Name `xml:"http://example.com/wssec DoThing"`
+	Value   string   `xml:"Value"`
+}
+
+func selfSignedCert(t *testing.T) (*x509.Certificate, *rsa.PrivateKey) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "gowsdl-test"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("ParseCertificate: %v", err)
+	}
+	return cert, key
+}
+
+// TestX509SignerSignsWhatGetsSent guards against the Body signature
+// going stale: Apply's digest has to be computed over the same wsu:Id
+// bearing bytes that marshaling the envelope afterward actually emits,
+// not a throwaway copy that's discarded.
+func TestX509SignerSignsWhatGetsSent(t *testing.T) {
+	cert, key := selfSignedCert(t)
+	env := &Envelope{
+		XMLName: xml.Name{Space: soap11Namespace, Local: "Envelope"},
+		Body:    &wssecTestBody{Value: "hello"},
+	}
+
+	signer := X509Signer{Cert: cert, Key: key}
+	if err := signer.Apply(env); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+
+	wire, err := xml.Marshal(env)
+	if err != nil {
+		t.Fatalf("marshal signed envelope: %v", err)
+	}
+
+	if !strings.Contains(string(wire), `Id="Body"`) {
+		t.Fatalf("signed envelope missing Body wsu:Id, got: %s", wire)
+	}
+	if !strings.Contains(string(wire), ">hello</Value>") {
+		t.Fatalf("signed envelope lost original Body content, got: %s", wire)
+	}
+
+	// The reference digest must match a canonicalization of the Body
+	// content as it actually appears on the wire, i.e. extracted from
+	// wire rather than recomputed from the original, unstamped env.Body.
+	start := bytes.Index(wire, []byte("<DoThing"))
+	end := bytes.Index(wire, []byte("</DoThing>")) + len("</DoThing>")
+	if start < 0 || end < 0 {
+		t.Fatalf("could not locate Body element in wire bytes: %s", wire)
+	}
+	wantDigest := signedReference("#Body", canonicalize(wire[start:end])).DigestValue
+
+	sec, ok := env.Header.Items[0].(*wsseSecurity)
+	if !ok {
+		t.Fatalf("Header.Items[0] = %T, want *wsseSecurity", env.Header.Items[0])
+	}
+	gotDigest := sec.Signature.SignedInfo.Reference[0].DigestValue
+	if gotDigest != wantDigest {
+		t.Fatalf("Body digest = %q, want %q (computed from the bytes actually on the wire)", gotDigest, wantDigest)
+	}
+}
+
+func TestInjectID(t *testing.T) {
+	out, err := injectID([]byte(`<Foo xmlns="http://example.com"><Bar>1</Bar></Foo>`), "Body")
+	if err != nil {
+		t.Fatalf("injectID: %v", err)
+	}
+	if !strings.Contains(string(out), `Id="Body"`) {
+		t.Fatalf("injectID did not add an Id attribute, got: %s", out)
+	}
+	if !strings.Contains(string(out), "<Bar>1</Bar>") {
+		t.Fatalf("injectID altered nested content, got: %s", out)
+	}
+}
+
+func TestUsernameTokenDigest(t *testing.T) {
+	env := &Envelope{XMLName: xml.Name{Space: soap11Namespace, Local: "Envelope"}}
+	token := UsernameToken{Username: "alice", Password: "secret", Digest: true}
+	if err := token.Apply(env); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+
+	sec, ok := env.Header.Items[0].(*wsseSecurity)
+	if !ok {
+		t.Fatalf("Header.Items[0] = %T, want *wsseSecurity", env.Header.Items[0])
+	}
+	if sec.UsernameToken.Password.Type != passwordTypeDigest {
+		t.Fatalf("Password.Type = %q, want %q", sec.UsernameToken.Password.Type, passwordTypeDigest)
+	}
+	if sec.UsernameToken.Password.Value == "secret" {
+		t.Fatal("digest password was sent in plaintext")
+	}
+}
@@ -30,6 +30,63 @@ var typesTmpl = `
 		{{end}}
 	)
 	{{end}}
+
+	{{template "ValidateFacets" (makeValidationData $typeName .Restriction)}}
+{{end}}
+
+{{define "ValidateFacets"}}
+	{{if and enableValidation .HasFacets}}
+		// Validate checks {{.TypeName}} against the XSD restriction facets
+		// declared for it in the schema.
+		func (x {{.TypeName}}) Validate() error {
+			{{if .Pattern}}
+				if !regexp.MustCompile(` + "`" + `^(?:{{.Pattern}})$` + "`" + `).MatchString(fmt.Sprintf("%v", x)) {
+					return fmt.Errorf("{{.TypeName}}: %q does not match pattern {{.Pattern}}", x)
+				}
+			{{end}}
+			{{if .MinLength}}
+				if utf8.RuneCountInString(fmt.Sprintf("%v", x)) < {{.MinLength}} {
+					return fmt.Errorf("{{.TypeName}}: %q is shorter than minLength {{.MinLength}}", x)
+				}
+			{{end}}
+			{{if .MaxLength}}
+				if utf8.RuneCountInString(fmt.Sprintf("%v", x)) > {{.MaxLength}} {
+					return fmt.Errorf("{{.TypeName}}: %q is longer than maxLength {{.MaxLength}}", x)
+				}
+			{{end}}
+			{{if .MinInclusive}}
+				if f, ok := soap.NumericValue(x); ok && f < {{.MinInclusive}} {
+					return fmt.Errorf("{{.TypeName}}: %v is less than minInclusive {{.MinInclusive}}", x)
+				}
+			{{end}}
+			{{if .MaxInclusive}}
+				if f, ok := soap.NumericValue(x); ok && f > {{.MaxInclusive}} {
+					return fmt.Errorf("{{.TypeName}}: %v is greater than maxInclusive {{.MaxInclusive}}", x)
+				}
+			{{end}}
+			{{if .MinExclusive}}
+				if f, ok := soap.NumericValue(x); ok && f <= {{.MinExclusive}} {
+					return fmt.Errorf("{{.TypeName}}: %v is not greater than minExclusive {{.MinExclusive}}", x)
+				}
+			{{end}}
+			{{if .MaxExclusive}}
+				if f, ok := soap.NumericValue(x); ok && f >= {{.MaxExclusive}} {
+					return fmt.Errorf("{{.TypeName}}: %v is not less than maxExclusive {{.MaxExclusive}}", x)
+				}
+			{{end}}
+			{{if .TotalDigits}}
+				if soap.TotalDigits(x) > {{.TotalDigits}} {
+					return fmt.Errorf("{{.TypeName}}: %v has more than totalDigits {{.TotalDigits}}", x)
+				}
+			{{end}}
+			{{if .FractionDigits}}
+				if soap.FractionDigits(x) > {{.FractionDigits}} {
+					return fmt.Errorf("{{.TypeName}}: %v has more than fractionDigits {{.FractionDigits}}", x)
+				}
+			{{end}}
+			return nil
+		}
+	{{end}}
 {{end}}
 
 {{define "ComplexContent"}}
@@ -42,7 +99,23 @@ var typesTmpl = `
 	{{template "Elements" .Extension.Sequence}}
 	{{template "Elements" .Extension.Choice}}
 	{{template "Elements" .Extension.SequenceChoice}}
+	{{template "Groups" .Extension.Group}}
 	{{template "Attributes" .Extension.Attributes}}
+	{{template "AttributeGroups" .Extension.AttributeGroup}}
+{{end}}
+
+{{define "Groups"}}
+	// Groups
+	{{range .}}
+		{{template "Elements" (resolveGroupRef .Ref)}}
+	{{end}}
+{{end}}
+
+{{define "AttributeGroups"}}
+	// AttributeGroups
+	{{range .}}
+		{{template "Attributes" (resolveAttributeGroupRef .Ref)}}
+	{{end}}
 {{end}}
 
 {{define "Attributes"}}
@@ -77,7 +150,9 @@ var typesTmpl = `
 			{{template "Elements" .Choice}}
 			{{template "Elements" .SequenceChoice}}
 			{{template "Elements" .All}}
+			{{template "Groups" .Group}}
 			{{template "Attributes" .Attributes}}
+			{{template "AttributeGroups" .AttributeGroup}}
 		{{end}}
 	{{end}}
 	} ` + "`" + `xml:"{{.Name}},omitempty" json:"{{.Name}},omitempty"` + "`" + `
@@ -87,7 +162,14 @@ var typesTmpl = `
 	// ElementsT
 	{{range .}}
 		{{if ne .Ref ""}}
-			{{removeNS .Ref | replaceReservedWords  | makePublic}} {{if eq .MaxOccurs "unbounded"}}[]{{end}}{{toGoType .Ref .Nillable }} ` + "`" + `xml:"{{.Ref | removeNS}},omitempty" json:"{{.Ref | removeNS}},omitempty"` + "`" + `
+			{{/* resolveElementRef follows the ref into whichever schema
+			     declares it -- including another imported namespace --
+			     and returns the concrete element's Go field name, type
+			     and XML local name, so a ref is indistinguishable from
+			     an inline declaration of the same element. */}}
+			{{$target := resolveElementRef .Ref}}
+			{{if $target.Doc}} {{$target.Doc | comment}} {{end}}
+			{{$target.FieldName}} {{if eq .MaxOccurs "unbounded"}}[]{{end}}{{$target.GoType}} ` + "`" + `xml:"{{$target.XMLName}},omitempty" json:"{{$target.XMLName}},omitempty"` + "`" + `
 		{{else}}
 		{{if not .Type}}
 			{{if .SimpleType}}
@@ -102,7 +184,11 @@ var typesTmpl = `
 			{{end}}
 		{{else}}
 			{{if .Doc}}{{.Doc | comment}} {{end}}
-			{{replaceAttrReservedWords .Name | makeFieldPublic}} {{if eq .MaxOccurs "unbounded"}}[]{{end}}{{toGoType .Type .Nillable }} ` + "`" + `xml:"{{.Name}},omitempty" json:"{{.Name}},omitempty"` + "`" + ` {{end}}
+			{{if ne .ExpectedContentTypes ""}}
+				{{replaceAttrReservedWords .Name | makeFieldPublic}} soap.MTOMAttachment ` + "`" + `xml:"{{.Name}},omitempty" json:"{{.Name}},omitempty"` + "`" + `
+			{{else}}
+				{{replaceAttrReservedWords .Name | makeFieldPublic}} {{if eq .MaxOccurs "unbounded"}}[]{{end}}{{toGoType .Type .Nillable }} ` + "`" + `xml:"{{.Name}},omitempty" json:"{{.Name}},omitempty"` + "`" + `
+			{{end}} {{end}}
 		{{end}}
 	{{end}}
 {{end}}
@@ -125,10 +211,12 @@ var typesTmpl = `
 		{{$name := .Name}}
 		// Elements {{$targetNamespace}}
 		{{$typeName := replaceReservedWords $name | wrapNS | makePublic}}
+		{{$elementDoc := .Doc}}
 		{{if not .Type}}
 			{{/* ComplexTypeLocal */}}
 			{{with .ComplexType}}
 				// ComplexTypeLocal $targetNamespace
+				{{if $elementDoc}} {{$elementDoc | comment}} {{end}}
 				type {{$typeName}} struct {
 					XMLName xml.Name ` + "`xml:\"{{$targetNamespace}} {{$name}}\"`" + `
 					{{if ne .ComplexContent.Extension.Base ""}}
@@ -141,7 +229,9 @@ var typesTmpl = `
 						{{template "Elements" .Choice}}
 						{{template "Elements" .SequenceChoice}}
 						{{template "Elements" .All}}
+						{{template "Groups" .Group}}
 						{{template "Attributes" .Attributes}}
+						{{template "AttributeGroups" .AttributeGroup}}
 					{{end}}
 				}
 			{{end}}
@@ -170,6 +260,8 @@ var typesTmpl = `
 					{{end}}
 				)
 				{{end}}
+
+				{{template "ValidateFacets" (makeValidationData $typeName .Restriction)}}
 			{{end}}
 		{{else}}
 			{{$type := toGoType .Type .Nillable | removePointerFromType}}
@@ -208,6 +300,7 @@ var typesTmpl = `
 		{{/* ComplexTypeGlobal */}}
 		// ComplexTypeGlobal {{ $targetNamespace }}
 		{{$typeName := replaceReservedWords .Name | wrapNS | makePublic}}
+		{{if .Doc}} {{.Doc | comment}} {{end}}
 		{{if and (eq (len .SimpleContent.Extension.Attributes) 0) (eq (toGoType .SimpleContent.Extension.Base false) "string") }}
 			type {{$typeName}} string
 		{{else}}
@@ -227,7 +320,9 @@ var typesTmpl = `
 					{{template "Elements" .Choice}}
 					{{template "Elements" .SequenceChoice}}
 					{{template "Elements" .All}}
+					{{template "Groups" .Group}}
 					{{template "Attributes" .Attributes}}
+					{{template "AttributeGroups" .AttributeGroup}}
 				{{end}}
 			}
 		{{end}}